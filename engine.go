@@ -1,13 +1,41 @@
 package hypermatcher
 
+import "context"
+
 // Engine is the hyperscanner pattern matching interface
 type Engine interface {
 	// Update rebuilds the pattern database, returning an optional error
 	Update(patterns []string) error
+	// UpdateContext is Update with a context, allowing a caller to bound how
+	// long it is willing to wait for the new pattern database to become active
+	UpdateContext(ctx context.Context, patterns []string) error
 	// Match takes a vectored byte corpus and returns a list of strings
 	// representing patterns that matched the corpus and an optional error
 	Match(corpus [][]byte) ([]string, error)
 	// Match takes a vectored string corpus and returns a list of strings
 	// representing patterns that matched the corpus and an optional error
 	MatchStrings(corpus []string) ([]string, error)
+	// MatchContext is Match with a context; if ctx is cancelled or its
+	// deadline expires before the scan completes, the in-flight scan is
+	// aborted and ctx.Err() is returned
+	MatchContext(ctx context.Context, corpus [][]byte) ([]string, error)
+	// MatchStringsContext is MatchStrings with a context, see MatchContext
+	MatchStringsContext(ctx context.Context, corpus []string) ([]string, error)
+	// Subscribe registers handler to be called with every future match of
+	// patternExpr found by the implementation's MatchDetailed or
+	// MatchStringsDetailed, returning a SubID that can later be passed to
+	// Unsubscribe. Matches found by Match, MatchStrings, their Context
+	// variants or a Stream are not delivered to handler
+	Subscribe(patternExpr string, handler func(MatchEvent), opts ...SubscriptionOption) (SubID, error)
+	// Unsubscribe removes a subscription previously registered with Subscribe
+	Unsubscribe(id SubID)
+}
+
+// StreamingMatcher is implemented by engines that support hyperscan's
+// native streaming mode, i.e. progressive/chunked input that can't be
+// materialized as a [][]byte corpus up front. StreamingEngine is the only
+// implementation today
+type StreamingMatcher interface {
+	// Open starts a new Stream against the currently loaded database
+	Open(ctx context.Context) (*Stream, error)
 }
@@ -1,22 +1,24 @@
 package hypermatcher
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 // Database is a database of compiled hyperscan patterns
 type Database interface {
 	// Update rebuilds the pattern database, returning an optional error
 	Update(patterns []string) error
+	// UpdateContext is Update with a context for cancellation and deadlines
+	UpdateContext(ctx context.Context, patterns []string) error
 	// Close releases all resources used by the database, returning an optional error
 	Close() error
 }
 
-// Engine is the hyperscan pattern matching interface
-type Engine interface {
-	// Match takes a vectored byte corpus and returns a slice of patterns that matched the corpus and an optional error
-	Match(corpus [][]byte) ([]string, error)
-	// MatchStrings takes a vectored string corpus and returns a slice of patterns that matched the corpus and an optional error
-	MatchStrings(corpus []string) ([]string, error)
-}
+// Engine lives in engine.go; it used to be redeclared here too, which kept
+// drifting out of sync with that copy as methods were added to one but not
+// the other. Database above embeds the subset of Engine's method set it
+// needs directly instead of depending on it.
 
 var (
 	// ErrNotLoaded is returned when Match() is invoked while the pattern database is not compiled and loaded
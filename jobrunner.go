@@ -0,0 +1,38 @@
+package hypermatcher
+
+import "context"
+
+// defaultQueueDepth bounds concurrent jobs when a JobRunner is constructed
+// without an explicit depth
+const defaultQueueDepth = 64
+
+// JobRunner bounds how many jobs run concurrently using a fixed-size
+// semaphore, giving callers backpressure (block until a slot frees up, or
+// bail out via ctx) instead of the saturated-pool rejection PooledEngine.Match
+// returns as ErrBusy
+type JobRunner struct {
+	sem chan struct{}
+}
+
+// NewJobRunner returns a JobRunner that admits at most queueDepth jobs at
+// once; extra Submit calls block until one finishes or ctx is cancelled
+func NewJobRunner(queueDepth int) *JobRunner {
+	if queueDepth < 1 {
+		queueDepth = defaultQueueDepth
+	}
+
+	return &JobRunner{sem: make(chan struct{}, queueDepth)}
+}
+
+// Submit blocks until a slot is free or ctx is cancelled, then runs job,
+// returning ctx.Err() if ctx was cancelled before a slot became available
+func (jr *JobRunner) Submit(ctx context.Context, job func() error) error {
+	select {
+	case jr.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-jr.sem }()
+
+	return job()
+}
@@ -0,0 +1,72 @@
+package hypermatcher
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_JobRunnerBoundsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var runner = NewJobRunner(2)
+	var inFlight, maxInFlight int32
+	var release = make(chan struct{})
+	var done = make(chan struct{})
+
+	for i := 0; i < 4; i++ {
+		go func() {
+			runner.Submit(context.Background(), func() error {
+				var current = atomic.AddInt32(&inFlight, 1)
+				for {
+					var observedMax = atomic.LoadInt32(&maxInFlight)
+					if current <= observedMax || atomic.CompareAndSwapInt32(&maxInFlight, observedMax, current) {
+						break
+					}
+				}
+				<-release
+				atomic.AddInt32(&inFlight, -1)
+				return nil
+			})
+			done <- struct{}{}
+		}()
+	}
+
+	// give the first two jobs a chance to claim their slots before we let
+	// any of them finish
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("got %d jobs in flight at once, want at most 2", got)
+	}
+}
+
+func Test_JobRunnerSubmitRespectsCancellation(t *testing.T) {
+	t.Parallel()
+
+	var runner = NewJobRunner(1)
+	var blockForever = make(chan struct{})
+	go runner.Submit(context.Background(), func() error {
+		<-blockForever
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	var err = runner.Submit(ctx, func() error {
+		t.Fatal("job should not run once the slot is still held and ctx is already cancelled")
+		return nil
+	})
+
+	if err != context.Canceled {
+		t.Errorf("got: %v, want: %v", err, context.Canceled)
+	}
+
+	close(blockForever)
+}
@@ -0,0 +1,110 @@
+package hypermatcher
+
+import "github.com/flier/gohs/hyperscan"
+
+// Match describes a single pattern hit produced by a detailed vectored scan.
+// Unlike the plain string APIs, it preserves every hit instead of
+// deduplicating matches into a set of expressions
+type Match struct {
+	// PatternID is the user ID hyperscan assigned the matching pattern,
+	// i.e. hyperscan.Pattern.Id; callers can use this to attach their own
+	// metadata to a pattern at registration time and look it back up here
+	PatternID int
+	// Expression is the raw pattern string that matched
+	Expression string
+	// BlockIndex is the index into the corpus passed to MatchDetailed of
+	// the input block this match ends in; a match spanning several blocks
+	// is attributed to the last of them
+	BlockIndex int
+	// From and To are the byte offsets of the match counted from the start
+	// of the first block, as if the corpus's blocks were one contiguous
+	// buffer (for a Stream, counted from the start of the stream). From is
+	// 0 unless the pattern was compiled with hyperscan's start-of-match flag
+	From, To uint64
+	// Flags are the hyperscan match flags reported for this hit
+	Flags uint
+	// Label is the caller-supplied metadata bound to the matching pattern
+	// via UpdateWithLabels, e.g. a rule ID, severity, or action code. It is
+	// nil for patterns registered through the plain Update/UpdateContext APIs
+	Label interface{}
+}
+
+// LabeledPattern binds a pattern to caller-supplied metadata, so that
+// MatchDetailed results can carry e.g. a rule ID, severity or action code
+// alongside the expression that matched, without the caller having to
+// maintain a side table keyed by pattern string or ID
+type LabeledPattern struct {
+	Pattern string
+	Label   interface{}
+}
+
+// detailedMatchContext is threaded through the context interface{} argument
+// of the hyperscan match callback so it can report which block a match
+// came from alongside the offsets hyperscan already provides. corpus is
+// scanned as a single vectored Scan call so that hyperscan can report
+// matches spanning block boundaries; blockLengths lets the handler recover
+// which block a match's offset falls in after the fact
+type detailedMatchContext struct {
+	blockLengths []int
+	patterns     []*hyperscan.Pattern
+	labels       []interface{}
+	matches      *[]Match
+}
+
+// detailedMatchHandler is a hyperscan.MatchHandler that appends a Match per
+// hit instead of deduplicating into a set of matched pattern indices
+func detailedMatchHandler(id uint, from, to uint64, flags uint, context interface{}) error {
+	var dmc = context.(*detailedMatchContext)
+	// IDs handed out by a patternCache are not positions in dmc.patterns,
+	// so look the pattern up by Id when its position does not hold it
+	var pos = int(id)
+	if pos >= len(dmc.patterns) || dmc.patterns[pos].Id != int(id) {
+		for idx, pattern := range dmc.patterns {
+			if pattern.Id == int(id) {
+				pos = idx
+				break
+			}
+		}
+	}
+	var label interface{}
+	if pos < len(dmc.labels) {
+		label = dmc.labels[pos]
+	}
+	// from is only set for start-of-match patterns, so the block is taken
+	// from the last byte of the match instead
+	var last = to
+	if last > 0 {
+		last--
+	}
+	*dmc.matches = append(*dmc.matches, Match{
+		PatternID:  int(id),
+		Expression: dmc.patterns[pos].Expression.String(),
+		BlockIndex: blockIndexForOffset(dmc.blockLengths, last),
+		From:       from,
+		To:         to,
+		Flags:      flags,
+		Label:      label,
+	})
+
+	return nil
+}
+
+// blockIndexForOffset returns the index of the block containing offset,
+// where offset is relative to the start of the first block - the same
+// virtual contiguous buffer hyperscan's vectored mode treats a multi-block
+// corpus as, which is what lets it report matches spanning two blocks
+func blockIndexForOffset(blockLengths []int, offset uint64) int {
+	if len(blockLengths) == 0 {
+		return 0
+	}
+
+	var cursor uint64
+	for idx, length := range blockLengths {
+		cursor += uint64(length)
+		if offset < cursor {
+			return idx
+		}
+	}
+
+	return len(blockLengths) - 1
+}
@@ -0,0 +1,182 @@
+package hypermatcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/flier/gohs/hyperscan"
+)
+
+// defaultPatternCacheSize is used when a PooledEngine is constructed
+// without an explicit pattern cache size
+const defaultPatternCacheSize = 4096
+
+// patternCache memoizes parsed patterns keyed by their raw string (flags
+// included), so that repeated Update/UpdateIncremental calls over a mostly
+// stable rule set skip reparsing the 99% of patterns that did not change.
+// Each cached pattern keeps a stable ID across cache hits, which in turn
+// lets the engine cache compiled databases by the sorted set of pattern IDs
+// they were built from
+type patternCache struct {
+	patterns *lru.Cache // raw pattern string -> *hyperscan.Pattern
+	dbs      *lru.Cache // sorted pattern-ID fingerprint -> hyperscan.VectoredDatabase
+	nextID   int
+	mu       sync.Mutex
+}
+
+// newPatternCache returns a patternCache holding up to size parsed patterns
+// and up to size compiled databases
+func newPatternCache(size int) (*patternCache, error) {
+	if size <= 0 {
+		size = defaultPatternCacheSize
+	}
+
+	var patterns, patternsErr = lru.New(size)
+	if patternsErr != nil {
+		return nil, patternsErr
+	}
+
+	var dbs, dbsErr = lru.NewWithEvict(size, func(_, value interface{}) {
+		if db, ok := value.(hyperscan.VectoredDatabase); ok {
+			db.Close()
+		}
+	})
+	if dbsErr != nil {
+		return nil, dbsErr
+	}
+
+	return &patternCache{patterns: patterns, dbs: dbs}, nil
+}
+
+// resolve returns the cached *hyperscan.Pattern for raw, parsing and
+// inserting it on a miss. A pattern's Id is assigned once and reused for as
+// long as it stays in the cache, which is what lets callers fingerprint a
+// pattern set by ID alone instead of re-hashing expressions every time
+func (pc *patternCache) resolve(raw string) (*hyperscan.Pattern, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if cached, ok := pc.patterns.Get(raw); ok {
+		return cached.(*hyperscan.Pattern), nil
+	}
+
+	var compiled, compileErr = hyperscan.ParsePattern(raw)
+	if compileErr != nil {
+		return nil, compileErr
+	}
+	compiled.Id = pc.nextID
+	pc.nextID++
+
+	pc.patterns.Add(raw, compiled)
+
+	return compiled, nil
+}
+
+// forget evicts raw from the pattern cache, e.g. when a pattern is removed
+// from the active rule set by UpdateIncremental
+func (pc *patternCache) forget(raw string) {
+	pc.mu.Lock()
+	pc.patterns.Remove(raw)
+	pc.mu.Unlock()
+}
+
+// fingerprint returns a stable key for a set of patterns based on their
+// (already-resolved) IDs, used to key the compiled-database cache
+func fingerprint(patterns []*hyperscan.Pattern) string {
+	var ids = make([]int, len(patterns))
+	for idx, pattern := range patterns {
+		ids[idx] = pattern.Id
+	}
+	sort.Ints(ids)
+
+	var parts = make([]string, len(ids))
+	for idx, id := range ids {
+		parts[idx] = strconv.Itoa(id)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// lookupDatabase returns a previously compiled database for the exact set
+// of pattern IDs in patterns, if still cached
+func (pc *patternCache) lookupDatabase(patterns []*hyperscan.Pattern) (hyperscan.VectoredDatabase, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	var cached, ok = pc.dbs.Get(fingerprint(patterns))
+	if !ok {
+		return nil, false
+	}
+
+	return cached.(hyperscan.VectoredDatabase), true
+}
+
+// storeDatabase caches db under the fingerprint of patterns; eviction
+// closes the evicted database so native memory is released
+// deterministically. Storing under a fingerprint that is already cached
+// replaces the entry without closing the database it held
+func (pc *patternCache) storeDatabase(patterns []*hyperscan.Pattern, db hyperscan.VectoredDatabase) {
+	pc.mu.Lock()
+	pc.dbs.Add(fingerprint(patterns), db)
+	pc.mu.Unlock()
+}
+
+// persistedDatabasePath returns where a database built from patterns would
+// be written under dir, so a process restart can skip compilation entirely.
+// The file name hashes each pattern's ID together with its expression and
+// flags, since IDs alone are only stable within one process; a database is
+// therefore only reloaded for the same ID-to-pattern assignment
+func persistedDatabasePath(dir string, patterns []*hyperscan.Pattern) string {
+	var entries = make([]string, len(patterns))
+	for idx, pattern := range patterns {
+		entries[idx] = strconv.Itoa(pattern.Id) + " " + strconv.Quote(pattern.Expression.String()) +
+			" " + strconv.FormatUint(uint64(pattern.Flags), 10)
+	}
+	sort.Strings(entries)
+	var sum = sha256.Sum256([]byte(strings.Join(entries, "\n")))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".hsdb")
+}
+
+// loadPersistedDatabase deserializes a previously persisted database for
+// patterns from dir, if present on disk
+func loadPersistedDatabase(dir string, patterns []*hyperscan.Pattern) (hyperscan.VectoredDatabase, bool) {
+	if dir == "" {
+		return nil, false
+	}
+
+	var raw, err = os.ReadFile(persistedDatabasePath(dir, patterns))
+	if err != nil {
+		return nil, false
+	}
+
+	db, err := hyperscan.DeserializeDatabase(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	return db.(hyperscan.VectoredDatabase), true
+}
+
+// persistDatabase serializes db to dir, under the persistedDatabasePath of
+// the patterns it was built from
+func persistDatabase(dir string, patterns []*hyperscan.Pattern, db hyperscan.VectoredDatabase) error {
+	if dir == "" {
+		return nil
+	}
+
+	var raw, err = hyperscan.SerializeDatabase(db)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(persistedDatabasePath(dir, patterns), raw, 0o600)
+}
@@ -0,0 +1,156 @@
+package hypermatcher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/flier/gohs/hyperscan"
+)
+
+// dbEnvelopeMagic identifies a SaveDatabase/LoadDatabase envelope
+var dbEnvelopeMagic = [4]byte{'H', 'M', 'D', 'B'}
+
+// dbEnvelopeVersion is the current on-disk envelope version; LoadDatabase
+// rejects anything else
+const dbEnvelopeVersion = 1
+
+// ErrIncompatiblePlatform is returned by LoadDatabase when the database was
+// built on a CPU feature set broader than the one available on this host
+var ErrIncompatiblePlatform = errors.New("database requires CPU features not available on this platform")
+
+// errBadEnvelope is returned by LoadDatabase when r does not contain a
+// recognizable envelope
+var errBadEnvelope = errors.New("not a hypermatcher database envelope")
+
+// saveDatabase writes db and patterns to w as a self-describing envelope: a
+// magic header, version byte, the hyperscan platform the database was built
+// for, the pattern-id-to-expression table, and the serialized database blob
+func saveDatabase(w io.Writer, patterns []*hyperscan.Pattern, db hyperscan.VectoredDatabase) error {
+	var bw = bufio.NewWriter(w)
+
+	if _, err := bw.Write(dbEnvelopeMagic[:]); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(dbEnvelopeVersion); err != nil {
+		return err
+	}
+
+	var platform = hyperscan.PopulatePlatform()
+	if err := binary.Write(bw, binary.LittleEndian, uint32(platform.Tune())); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint64(platform.CpuFeatures())); err != nil {
+		return err
+	}
+
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(patterns))); err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if err := binary.Write(bw, binary.LittleEndian, int32(pattern.Id)); err != nil {
+			return err
+		}
+		var expr = pattern.Expression.String()
+		if err := binary.Write(bw, binary.LittleEndian, uint32(len(expr))); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(expr); err != nil {
+			return err
+		}
+	}
+
+	var blob, serializeErr = hyperscan.SerializeDatabase(db)
+	if serializeErr != nil {
+		return fmt.Errorf("error serializing pattern database: %s", serializeErr.Error())
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(blob))); err != nil {
+		return err
+	}
+	if _, err := bw.Write(blob); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// loadDatabase reads an envelope written by saveDatabase back from r,
+// rejecting it if the database was built for a CPU feature set this host
+// does not have
+func loadDatabase(r io.Reader) ([]*hyperscan.Pattern, hyperscan.VectoredDatabase, error) {
+	var br = bufio.NewReader(r)
+
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+	if magic != dbEnvelopeMagic {
+		return nil, nil, errBadEnvelope
+	}
+
+	var version byte
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+	if version != dbEnvelopeVersion {
+		return nil, nil, fmt.Errorf("unsupported database envelope version %d", version)
+	}
+
+	var tune uint32
+	var cpuFeatures uint64
+	if err := binary.Read(br, binary.LittleEndian, &tune); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+	if err := binary.Read(br, binary.LittleEndian, &cpuFeatures); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+
+	var host = hyperscan.PopulatePlatform()
+	if uint64(host.CpuFeatures())&cpuFeatures != cpuFeatures {
+		return nil, nil, ErrIncompatiblePlatform
+	}
+
+	var patternCount uint32
+	if err := binary.Read(br, binary.LittleEndian, &patternCount); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+	var patterns = make([]*hyperscan.Pattern, patternCount)
+	for idx := range patterns {
+		var id int32
+		if err := binary.Read(br, binary.LittleEndian, &id); err != nil {
+			return nil, nil, errBadEnvelope
+		}
+		var exprLen uint32
+		if err := binary.Read(br, binary.LittleEndian, &exprLen); err != nil {
+			return nil, nil, errBadEnvelope
+		}
+		var exprBytes = make([]byte, exprLen)
+		if _, err := io.ReadFull(br, exprBytes); err != nil {
+			return nil, nil, errBadEnvelope
+		}
+		var pattern, parseErr = hyperscan.ParsePattern(string(exprBytes))
+		if parseErr != nil {
+			return nil, nil, fmt.Errorf("error parsing persisted pattern: %s", parseErr.Error())
+		}
+		pattern.Id = int(id)
+		patterns[idx] = pattern
+	}
+
+	var blobLen uint32
+	if err := binary.Read(br, binary.LittleEndian, &blobLen); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+	var blob = make([]byte, blobLen)
+	if _, err := io.ReadFull(br, blob); err != nil {
+		return nil, nil, errBadEnvelope
+	}
+
+	var db, deserializeErr = hyperscan.DeserializeDatabase(blob)
+	if deserializeErr != nil {
+		return nil, nil, fmt.Errorf("error deserializing pattern database: %s", deserializeErr.Error())
+	}
+
+	return patterns, db.(hyperscan.VectoredDatabase), nil
+}
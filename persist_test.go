@@ -0,0 +1,34 @@
+package hypermatcher
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_SimpleSaveLoadDatabaseRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	var saved = NewSimple()
+	if err := saved.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := saved.SaveDatabase(&buf); err != nil {
+		t.Fatalf("SaveDatabase failed: %v", err)
+	}
+
+	var loaded = NewSimple()
+	if err := loaded.LoadDatabase(&buf); err != nil {
+		t.Fatalf("LoadDatabase failed: %v", err)
+	}
+
+	var matches, err = loaded.MatchStrings([]string{"corpus"})
+	if err != nil {
+		t.Fatalf("MatchStrings against the loaded database failed: %v", err)
+	}
+	if want := []string{"corpus"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("got: %#v, want: %#v", matches, want)
+	}
+}
@@ -1,26 +1,88 @@
 package hypermatcher
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/flier/gohs/hyperscan"
 )
 
+// defaultMinShardSize is the smallest per-worker corpus shard MatchSharded
+// will create; corpora below this size are scanned by a single worker
+// instead of being split, since the goroutine fan-out isn't worth it
+const defaultMinShardSize = 64
+
 // PooledEngine is a concurrent hypermatcher.Engine implementation
 // backed by a pool of goroutines with individual scratch space
 type PooledEngine struct {
-	requestChan chan concurrentScanRequest
-	stopChan    chan struct{}
-	workers     []*poolWorker
-	patterns    []*hyperscan.Pattern
-	db          hyperscan.VectoredDatabase
-	loaded      bool
-	started     bool
-	mu          sync.RWMutex
+	requestChan    chan concurrentScanRequest
+	stopChan       chan struct{}
+	workers        []*poolWorker
+	patterns       []*hyperscan.Pattern
+	db             hyperscan.VectoredDatabase
+	loaded         bool
+	started        bool
+	maxConcurrency int
+	minShardSize   int
+	patternCache   *patternCache
+	persistDir     string
+	activePatterns []string
+	patternsByID   map[int]*hyperscan.Pattern
+	labels         []interface{}
+	runner         *JobRunner
+	subs           *subscriptions
+	mu             sync.RWMutex
+}
+
+// PooledEngineOption configures optional PooledEngine behavior
+type PooledEngineOption func(*PooledEngine)
+
+// WithMaxConcurrency caps the number of shards MatchSharded fans a single
+// corpus out to; it defaults to the number of workers in the pool
+func WithMaxConcurrency(n int) PooledEngineOption {
+	return func(pe *PooledEngine) { pe.maxConcurrency = n }
+}
+
+// WithMinShardSize sets the smallest corpus shard MatchSharded will create,
+// see defaultMinShardSize
+func WithMinShardSize(n int) PooledEngineOption {
+	return func(pe *PooledEngine) { pe.minShardSize = n }
+}
+
+// WithPatternCache enables UpdateIncremental by giving the engine an LRU
+// cache of up to size parsed patterns and compiled databases, so repeated
+// incremental updates over a mostly-stable rule set skip reparsing and
+// recompiling the patterns that did not change
+func WithPatternCache(size int) PooledEngineOption {
+	return func(pe *PooledEngine) {
+		var cache, err = newPatternCache(size)
+		if err != nil {
+			return
+		}
+		pe.patternCache = cache
+	}
+}
+
+// WithPersistDir points the engine's pattern cache at an on-disk directory
+// used to save and reload compiled databases across process restarts; it
+// has no effect unless WithPatternCache is also set
+func WithPersistDir(dir string) PooledEngineOption {
+	return func(pe *PooledEngine) { pe.persistDir = dir }
+}
+
+// WithQueueDepth bounds MatchContext to at most queueDepth concurrent
+// in-flight calls via a JobRunner, so callers beyond that block for a free
+// slot (or bail out via ctx) instead of piling up behind the pool's
+// requestChan. Without this option MatchContext is bounded only by the
+// number of workers in the pool
+func WithQueueDepth(queueDepth int) PooledEngineOption {
+	return func(pe *PooledEngine) { pe.runner = NewJobRunner(queueDepth) }
 }
 
 type concurrentScanRequest struct {
+	ctx          context.Context
 	blocks       [][]byte
 	responseChan chan concurrentScanResponse
 }
@@ -31,16 +93,85 @@ type concurrentScanResponse struct {
 }
 
 // NewPooledEngine returns a PooledEngine
-func NewPooledEngine(numWorkers int) *PooledEngine {
-	return &PooledEngine{
-		requestChan: make(chan concurrentScanRequest),
-		stopChan:    make(chan struct{}),
-		workers:     make([]*poolWorker, numWorkers),
-		patterns:    make([]*hyperscan.Pattern, 0),
-		loaded:      false,
-		started:     false,
-		mu:          sync.RWMutex{},
+func NewPooledEngine(numWorkers int, opts ...PooledEngineOption) *PooledEngine {
+	var pe = &PooledEngine{
+		requestChan:    make(chan concurrentScanRequest),
+		stopChan:       make(chan struct{}),
+		workers:        make([]*poolWorker, numWorkers),
+		patterns:       make([]*hyperscan.Pattern, 0),
+		loaded:         false,
+		started:        false,
+		maxConcurrency: numWorkers,
+		minShardSize:   defaultMinShardSize,
+		patternsByID:   make(map[int]*hyperscan.Pattern),
+		mu:             sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt(pe)
+	}
+
+	return pe
+}
+
+// NewPooledEngineWithCache is NewPooledEngine with WithPatternCache(size)
+// applied, so Update (and UpdateIncremental) reuse a previously compiled
+// database whenever Update is called again with a pattern set whose
+// fingerprint is still in the cache - the common case for a server that
+// rotates between a small number of rule bundles
+func NewPooledEngineWithCache(numWorkers, size int) *PooledEngine {
+	return NewPooledEngine(numWorkers, WithPatternCache(size))
+}
+
+// updateCached is Update backed by pe.patternCache: every pattern is
+// resolved to a stable ID (reparsing only on a cache miss), and the
+// resulting pattern set's database is reused if its ID fingerprint was
+// compiled before
+func (pe *PooledEngine) updateCached(patterns []string) error {
+	var cache = pe.patternCache
+
+	var compiledPatterns = make([]*hyperscan.Pattern, 0, len(patterns))
+	for _, raw := range patterns {
+		var compiled, compileErr = cache.resolve(raw)
+		if compileErr != nil {
+			return fmt.Errorf("error updating pattern database: %s", compileErr.Error())
+		}
+		compiledPatterns = append(compiledPatterns, compiled)
+	}
+
+	var newDB, cached = cache.lookupDatabase(compiledPatterns)
+	if !cached {
+		if persisted, ok := loadPersistedDatabase(pe.persistDir, compiledPatterns); ok {
+			newDB = persisted
+		} else {
+			var built, dbErr = buildDatabase(compiledPatterns)
+			if dbErr != nil {
+				return fmt.Errorf("error updating pattern database: %s", dbErr.Error())
+			}
+			newDB = built
+			if persistErr := persistDatabase(pe.persistDir, compiledPatterns, newDB); persistErr != nil {
+				return fmt.Errorf("error persisting pattern database: %s", persistErr.Error())
+			}
+		}
+		cache.storeDatabase(compiledPatterns, newDB)
+	}
+
+	// the previous pe.db (if any) is still reachable from pe.patternCache.dbs
+	// under its own fingerprint, so it is never closed here - the cache's own
+	// eviction callback (see newPatternCache) is the sole closer of a
+	// cache-tracked database
+	pe.mu.Lock()
+	pe.db = newDB
+	pe.patterns = compiledPatterns
+	pe.activePatterns = append([]string(nil), patterns...)
+	pe.labels = nil
+	pe.loaded = true
+	pe.mu.Unlock()
+
+	for _, worker := range pe.workers {
+		worker.refreshChan <- newDB
 	}
+
+	return nil
 }
 
 // Update re-initializes the pattern database used by the
@@ -54,11 +185,19 @@ func (pe *PooledEngine) Update(patterns []string) error {
 	var started bool
 	pe.mu.RLock()
 	started = pe.started
+	var cache = pe.patternCache
 	pe.mu.RUnlock()
 	if !started {
 		return ErrNotStarted
 	}
 
+	if cache != nil {
+		// the pool was built with WithPatternCache/NewPooledEngineWithCache:
+		// reuse this exact pattern set's compiled database if we have
+		// already built it once before, instead of recompiling from scratch
+		return pe.updateCached(patterns)
+	}
+
 	// compile patterns and add them to the internal list, returning
 	// an error on the first pattern that fails to parse
 	var db, compiledPatterns, dbErr = compilePatterns(patterns)
@@ -73,7 +212,9 @@ func (pe *PooledEngine) Update(patterns []string) error {
 	}
 	pe.db = db
 	pe.patterns = compiledPatterns
+	pe.activePatterns = append([]string(nil), patterns...)
 	pe.loaded = true
+	pe.labels = nil
 	pe.mu.Unlock()
 	// send the new database to the workers
 	for _, worker := range pe.workers {
@@ -83,6 +224,115 @@ func (pe *PooledEngine) Update(patterns []string) error {
 	return nil
 }
 
+// UpdateWithLabels is Update, but binds each pattern to caller-supplied
+// metadata (a rule ID, severity, action code, etc.) that MatchDetailed
+// attaches to every Match produced by that pattern
+func (pe *PooledEngine) UpdateWithLabels(patterns []LabeledPattern) error {
+	var raw = make([]string, len(patterns))
+	var labels = make([]interface{}, len(patterns))
+	for idx, labeledPattern := range patterns {
+		raw[idx] = labeledPattern.Pattern
+		labels[idx] = labeledPattern.Label
+	}
+
+	if err := pe.Update(raw); err != nil {
+		return err
+	}
+
+	pe.mu.Lock()
+	pe.labels = labels
+	pe.mu.Unlock()
+
+	return nil
+}
+
+// MatchDetailed takes a vectored byte corpus and returns every match found,
+// including its pattern ID, expression, originating block index, byte
+// offsets, flags and label - unlike Match, hits are not deduplicated into a
+// set of expressions. It scans directly against the pool's shared database
+// with its own scratch rather than going through the worker pool, since
+// threading per-hit detail through the pool's request/response channels
+// would otherwise require a channel of []Match per request. The whole
+// corpus is scanned in a single vectored Scan call, the same way Match does
+// it, so a pattern match spanning two blocks is still reported; BlockIndex
+// is recovered afterwards from the match offset
+func (pe *PooledEngine) MatchDetailed(corpus [][]byte) ([]Match, error) {
+	pe.mu.RLock()
+	var loaded, started = pe.loaded, pe.started
+	pe.mu.RUnlock()
+	switch {
+	case !loaded:
+		return nil, ErrDBNotLoaded
+	case !started:
+		return nil, ErrNotStarted
+	}
+
+	var blockLengths = make([]int, len(corpus))
+	for idx, block := range corpus {
+		blockLengths[idx] = len(block)
+	}
+
+	pe.mu.RLock()
+	var scratch, scratchErr = hyperscan.NewScratch(pe.db)
+	if scratchErr != nil {
+		pe.mu.RUnlock()
+		return nil, scratchErr
+	}
+	defer scratch.Free()
+
+	var matches = make([]Match, 0)
+	var subs = pe.subs
+	var dmc = &detailedMatchContext{blockLengths: blockLengths, patterns: pe.patterns, labels: pe.labels, matches: &matches}
+	var scanErr = pe.db.Scan(corpus, scratch, detailedMatchHandler, dmc)
+	pe.mu.RUnlock()
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	if subs != nil {
+		subs.publishAll(matches)
+	}
+
+	return matches, nil
+}
+
+// Subscribe registers handler to be invoked asynchronously, on a dedicated
+// dispatch goroutine, for every match of patternExpr found by a later
+// MatchDetailed/MatchStringsDetailed call. Returns ErrUnknownPattern if
+// patternExpr is not part of the currently loaded pattern set
+func (pe *PooledEngine) Subscribe(patternExpr string, handler func(MatchEvent), opts ...SubscriptionOption) (SubID, error) {
+	pe.mu.RLock()
+	var patternID, err = resolvePatternID(pe.patterns, patternExpr)
+	pe.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	pe.mu.Lock()
+	if pe.subs == nil {
+		pe.subs = newSubscriptions()
+	}
+	var subs = pe.subs
+	pe.mu.Unlock()
+
+	return subs.subscribe(patternID, handler, opts...), nil
+}
+
+// Unsubscribe cancels a subscription previously returned by Subscribe
+func (pe *PooledEngine) Unsubscribe(id SubID) {
+	pe.mu.RLock()
+	var subs = pe.subs
+	pe.mu.RUnlock()
+	if subs != nil {
+		subs.unsubscribe(id)
+	}
+}
+
+// MatchStringsDetailed is MatchDetailed over a string corpus
+func (pe *PooledEngine) MatchStringsDetailed(corpus []string) ([]Match, error) {
+	return pe.MatchDetailed(stringsToBytes(corpus))
+}
+
 // Match takes a vectored byte corpus and returns a list of strings
 // representing patterns that matched the corpus and an optional error
 func (pe *PooledEngine) Match(corpus [][]byte) ([]string, error) {
@@ -101,6 +351,7 @@ func (pe *PooledEngine) Match(corpus [][]byte) ([]string, error) {
 	// mode, returning an error if all workers are busy
 	// the response is read from a per-request channel
 	var request = concurrentScanRequest{
+		ctx:          context.Background(),
 		blocks:       corpus,
 		responseChan: make(chan concurrentScanResponse),
 	}
@@ -129,6 +380,404 @@ func (pe *PooledEngine) MatchStrings(corpus []string) ([]string, error) {
 	return pe.Match(stringsToBytes(corpus))
 }
 
+// SaveDatabase writes the currently loaded pattern database to w so a later
+// process can skip recompiling the same patterns via LoadDatabase
+func (pe *PooledEngine) SaveDatabase(w io.Writer) error {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	if !pe.loaded {
+		return ErrDBNotLoaded
+	}
+
+	return saveDatabase(w, pe.patterns, pe.db)
+}
+
+// LoadDatabase replaces the pattern database with one previously written by
+// SaveDatabase, rejecting it if it was built for a CPU feature set this host
+// does not have. The new database is pushed to every worker through
+// refreshChan the same way Update does
+func (pe *PooledEngine) LoadDatabase(r io.Reader) error {
+	pe.mu.RLock()
+	var started = pe.started
+	pe.mu.RUnlock()
+	if !started {
+		return ErrNotStarted
+	}
+
+	var patterns, db, err = loadDatabase(r)
+	if err != nil {
+		return err
+	}
+
+	var byID = make(map[int]*hyperscan.Pattern, len(patterns))
+	var raw = make([]string, len(patterns))
+	for idx, pattern := range patterns {
+		byID[pattern.Id] = pattern
+		raw[idx] = pattern.Expression.String()
+	}
+
+	pe.mu.Lock()
+	var oldDB, cache = pe.db, pe.patternCache
+	pe.db = db
+	pe.patterns = patterns
+	pe.patternsByID = byID
+	pe.activePatterns = raw
+	pe.labels = nil
+	pe.loaded = true
+	pe.mu.Unlock()
+	// if a pattern cache is in use, oldDB may still be reachable from
+	// pe.patternCache.dbs under its own fingerprint; only the cache's own
+	// eviction callback may close it in that case, so we only close it
+	// directly here when there is no cache to dispute ownership
+	if cache == nil && oldDB != nil && oldDB != db {
+		oldDB.Close()
+	}
+
+	for _, worker := range pe.workers {
+		worker.refreshChan <- db
+	}
+
+	return nil
+}
+
+// UpdateContext is Update with a context for cancellation and deadlines
+func (pe *PooledEngine) UpdateContext(ctx context.Context, patterns []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return pe.Update(patterns)
+}
+
+// errNoPatternCache is returned by UpdateIncremental when the engine was
+// not constructed with WithPatternCache
+var errNoPatternCache = fmt.Errorf("engine has no pattern cache, construct it with WithPatternCache")
+
+// UpdateIncremental applies add/remove to the currently active pattern set
+// instead of recompiling it from scratch: unchanged patterns are served from
+// the engine's pattern cache, and if the resulting pattern set (by stable
+// pattern ID) was compiled before, its database is reused rather than
+// rebuilt. Requires the engine to have been constructed with
+// WithPatternCache
+func (pe *PooledEngine) UpdateIncremental(add, remove []string) error {
+	pe.mu.RLock()
+	var started, cache = pe.started, pe.patternCache
+	pe.mu.RUnlock()
+	if !started {
+		return ErrNotStarted
+	}
+	if cache == nil {
+		return errNoPatternCache
+	}
+
+	pe.mu.RLock()
+	var removeSet = make(map[string]struct{}, len(remove))
+	for _, raw := range remove {
+		removeSet[raw] = struct{}{}
+	}
+	var activeRaw = make([]string, 0, len(pe.activePatterns)+len(add))
+	for _, raw := range pe.activePatterns {
+		if _, removed := removeSet[raw]; !removed {
+			activeRaw = append(activeRaw, raw)
+		}
+	}
+	pe.mu.RUnlock()
+	activeRaw = append(activeRaw, add...)
+	if len(activeRaw) == 0 {
+		return ErrNoPatterns
+	}
+
+	for _, raw := range remove {
+		cache.forget(raw)
+	}
+
+	var compiledPatterns = make([]*hyperscan.Pattern, 0, len(activeRaw))
+	for _, raw := range activeRaw {
+		var compiled, compileErr = cache.resolve(raw)
+		if compileErr != nil {
+			return fmt.Errorf("error updating pattern database: %s", compileErr.Error())
+		}
+		compiledPatterns = append(compiledPatterns, compiled)
+	}
+
+	var newDB, cached = cache.lookupDatabase(compiledPatterns)
+	if !cached {
+		if persisted, ok := loadPersistedDatabase(pe.persistDir, compiledPatterns); ok {
+			newDB = persisted
+		} else {
+			var built, dbErr = buildDatabase(compiledPatterns)
+			if dbErr != nil {
+				return fmt.Errorf("error updating pattern database: %s", dbErr.Error())
+			}
+			newDB = built
+			if persistErr := persistDatabase(pe.persistDir, compiledPatterns, newDB); persistErr != nil {
+				return fmt.Errorf("error persisting pattern database: %s", persistErr.Error())
+			}
+		}
+		cache.storeDatabase(compiledPatterns, newDB)
+	}
+
+	var byID = make(map[int]*hyperscan.Pattern, len(compiledPatterns))
+	for _, pattern := range compiledPatterns {
+		byID[pattern.Id] = pattern
+	}
+
+	// as in updateCached, the previous pe.db is still reachable from
+	// pe.patternCache.dbs under its own fingerprint, so it is never closed
+	// here - the cache's eviction callback is the sole closer
+	pe.mu.Lock()
+	pe.db = newDB
+	pe.patterns = compiledPatterns
+	pe.patternsByID = byID
+	pe.activePatterns = activeRaw
+	pe.loaded = true
+	pe.mu.Unlock()
+
+	for _, worker := range pe.workers {
+		worker.refreshChan <- newDB
+	}
+
+	return nil
+}
+
+// MatchContext is Match with a context: unlike Match, it blocks waiting for
+// a free worker instead of immediately returning ErrBusy, and unblocks early
+// with ctx.Err() if ctx is cancelled or its deadline expires first. If a
+// worker does pick up the request, ctx is also handed to the hyperscan
+// callback so an in-flight scan can be aborted mid-corpus. If the engine was
+// constructed with WithQueueDepth, MatchContext is additionally bounded to
+// at most that many concurrent in-flight calls, blocking for a free slot
+// before it ever reaches the worker pool
+func (pe *PooledEngine) MatchContext(ctx context.Context, corpus [][]byte) ([]string, error) {
+	pe.mu.RLock()
+	var loaded, started, runner = pe.loaded, pe.started, pe.runner
+	pe.mu.RUnlock()
+	switch {
+	case !loaded:
+		return nil, ErrDBNotLoaded
+	case !started:
+		return nil, ErrNotStarted
+	}
+
+	if runner != nil {
+		var matchedPatterns []string
+		var err = runner.Submit(ctx, func() error {
+			var matchErr error
+			matchedPatterns, matchErr = pe.dispatchMatch(ctx, corpus)
+			return matchErr
+		})
+		if err != nil {
+			return nil, err
+		}
+		return matchedPatterns, nil
+	}
+
+	return pe.dispatchMatch(ctx, corpus)
+}
+
+// dispatchMatch sends corpus to the worker pool and waits for a response,
+// unblocking early with ctx.Err() if ctx is cancelled or its deadline
+// expires first
+func (pe *PooledEngine) dispatchMatch(ctx context.Context, corpus [][]byte) ([]string, error) {
+	var request = concurrentScanRequest{
+		ctx:          ctx,
+		blocks:       corpus,
+		responseChan: make(chan concurrentScanResponse),
+	}
+	var response concurrentScanResponse
+	select {
+	case pe.requestChan <- request: // request sent, must wait for response
+		select {
+		case response = <-request.responseChan:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if response.err != nil {
+		return nil, response.err
+	}
+
+	return matchedIdxToStrings(response.matched, pe.patterns, &pe.mu), nil
+}
+
+// ForEachCorpus runs MatchContext over every corpus in corpora, bounded by
+// the engine's worker pool (and, if configured, WithQueueDepth) the same way
+// a single MatchContext call would be, and invokes fn with each corpus's
+// index and match result as it completes. It fans out all of corpora
+// concurrently and returns the first error encountered, either from a scan
+// or from fn itself; ctx cancellation stops corpora that have not yet
+// started and is propagated to in-flight ones
+func (pe *PooledEngine) ForEachCorpus(ctx context.Context, corpora [][][]byte, fn func(idx int, result []string) error) error {
+	var ctx2, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for idx, corpus := range corpora {
+		wg.Add(1)
+		go func(idx int, corpus [][]byte) {
+			defer wg.Done()
+			var result, matchErr = pe.MatchContext(ctx2, corpus)
+			if matchErr == nil {
+				matchErr = fn(idx, result)
+			}
+			if matchErr != nil {
+				once.Do(func() {
+					firstErr = matchErr
+					cancel()
+				})
+			}
+		}(idx, corpus)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// MatchStringsContext is MatchStrings with a context, see MatchContext
+func (pe *PooledEngine) MatchStringsContext(ctx context.Context, corpus []string) ([]string, error) {
+	return pe.MatchContext(ctx, stringsToBytes(corpus))
+}
+
+// MatchSharded partitions corpus into K shards (K = min(maxConcurrency,
+// len(corpus)), corpora under minShardSize are not split at all) and
+// submits each shard as an independent scan request so every idle worker in
+// the pool helps with a single Match call instead of one worker handling
+// the whole corpus while the rest sit idle. The per-shard matched pattern
+// indices are unioned before being mapped back to expression strings
+func (pe *PooledEngine) MatchSharded(ctx context.Context, corpus [][]byte) ([]string, error) {
+	pe.mu.RLock()
+	var loaded, started = pe.loaded, pe.started
+	var maxConcurrency, minShardSize = pe.maxConcurrency, pe.minShardSize
+	pe.mu.RUnlock()
+	switch {
+	case !loaded:
+		return nil, ErrDBNotLoaded
+	case !started:
+		return nil, ErrNotStarted
+	}
+
+	var shards = shardCorpus(corpus, maxConcurrency, minShardSize)
+	var responseChan = make(chan concurrentScanResponse, len(shards))
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Add(1)
+		go func(blocks [][]byte) {
+			defer wg.Done()
+			responseChan <- pe.submitShard(ctx, blocks)
+		}(shard)
+	}
+	go func() {
+		wg.Wait()
+		close(responseChan)
+	}()
+
+	var matchedSieve = make(map[uint]struct{}, 0)
+	for response := range responseChan {
+		if response.err != nil {
+			return nil, response.err
+		}
+		for _, patIdx := range response.matched {
+			matchedSieve[patIdx] = struct{}{}
+		}
+	}
+
+	var matched = make([]uint, 0, len(matchedSieve))
+	for patIdx := range matchedSieve {
+		matched = append(matched, patIdx)
+	}
+
+	return matchedIdxToStrings(matched, pe.patterns, &pe.mu), nil
+}
+
+// MatchStringsSharded is MatchSharded over a string corpus
+func (pe *PooledEngine) MatchStringsSharded(ctx context.Context, corpus []string) ([]string, error) {
+	return pe.MatchSharded(ctx, stringsToBytes(corpus))
+}
+
+// submitShard sends a single shard to the worker pool, blocking until a
+// worker picks it up or ctx is done
+func (pe *PooledEngine) submitShard(ctx context.Context, blocks [][]byte) concurrentScanResponse {
+	var request = concurrentScanRequest{
+		ctx:          ctx,
+		blocks:       blocks,
+		responseChan: make(chan concurrentScanResponse),
+	}
+	select {
+	case pe.requestChan <- request:
+	case <-ctx.Done():
+		return concurrentScanResponse{err: ctx.Err()}
+	}
+
+	select {
+	case response := <-request.responseChan:
+		return response
+	case <-ctx.Done():
+		return concurrentScanResponse{err: ctx.Err()}
+	}
+}
+
+// matchedIdxToStrings maps matched pattern IDs back to their expression
+// strings, deduplicating repeats, in the order the patterns appear in
+// patterns. IDs are matched against each pattern's Id rather than its
+// position, since IDs handed out by a patternCache are not positions. It
+// takes mu so callers can hand it their patterns slice without racing a
+// concurrent Update/UpdateIncremental/LoadDatabase call that might replace it
+func matchedIdxToStrings(matched []uint, patterns []*hyperscan.Pattern, mu *sync.RWMutex) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var sieve = make(map[uint]struct{}, len(matched))
+	for _, idx := range matched {
+		sieve[idx] = struct{}{}
+	}
+
+	var matchedPatterns = make([]string, 0, len(sieve))
+	for _, pattern := range patterns {
+		if _, ok := sieve[uint(pattern.Id)]; ok {
+			matchedPatterns = append(matchedPatterns, pattern.Expression.String())
+		}
+	}
+
+	return matchedPatterns
+}
+
+// shardCorpus splits corpus into up to maxConcurrency contiguous shards, each
+// at least minShardSize blocks, so tiny corpora aren't over-sharded
+func shardCorpus(corpus [][]byte, maxConcurrency, minShardSize int) [][][]byte {
+	var numShards = maxConcurrency
+	if byShardSize := len(corpus) / minShardSize; byShardSize < numShards {
+		numShards = byShardSize
+	}
+	if numShards < 1 {
+		numShards = 1
+	}
+	if numShards > len(corpus) {
+		numShards = len(corpus)
+	}
+	if numShards < 1 {
+		numShards = 1
+	}
+
+	// spread the remainder over the first shards, so that no shard is more
+	// than one block smaller than another and none falls under minShardSize
+	var shards = make([][][]byte, 0, numShards)
+	var start = 0
+	for idx := 0; idx < numShards && start < len(corpus); idx++ {
+		var end = start + len(corpus)/numShards
+		if idx < len(corpus)%numShards {
+			end++
+		}
+		shards = append(shards, corpus[start:end])
+		start = end
+	}
+
+	return shards
+}
+
 // Start starts the workers backing the concurrent engine
 func (pe *PooledEngine) Start() error {
 	pe.mu.Lock()
@@ -160,11 +809,14 @@ func (pe *PooledEngine) Stop() error {
 	// close stopChan, signaling workers to stop
 	close(pe.stopChan)
 
-	// close the database if it is loaded
-	if pe.loaded {
+	// close the database if it is loaded, unless a pattern cache is in use -
+	// pe.db may still be reachable from pe.patternCache.dbs under its own
+	// fingerprint, in which case only the cache's own eviction callback may
+	// close it
+	if pe.loaded && pe.patternCache == nil {
 		pe.db.Close()
-		pe.loaded = false
 	}
+	pe.loaded = false
 
 	pe.started = false
 
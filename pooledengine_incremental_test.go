@@ -0,0 +1,70 @@
+package hypermatcher
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Test_PooledEngineUpdateIncrementalAddsAndRemoves exercises UpdateIncremental
+// end to end: adding a pattern makes it match, and removing the original
+// pattern set stops matching it, without ever calling Update again
+func Test_PooledEngineUpdateIncrementalAddsAndRemoves(t *testing.T) {
+	t.Parallel()
+
+	var warmUpTime = time.Millisecond
+	var engine = NewPooledEngineWithCache(runtime.NumCPU(), 8)
+	engine.Start()
+	defer engine.Stop()
+
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	if err := engine.UpdateIncremental([]string{"/other/"}, nil); err != nil {
+		t.Fatalf("UpdateIncremental(add) failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	var matches, err = engine.MatchStrings([]string{"corpus and other"})
+	if err != nil {
+		t.Fatalf("MatchStrings failed: %v", err)
+	}
+	if want := []string{"corpus", "other"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("got: %#v, want: %#v", matches, want)
+	}
+
+	if err := engine.UpdateIncremental(nil, []string{"/corpus/"}); err != nil {
+		t.Fatalf("UpdateIncremental(remove) failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	matches, err = engine.MatchStrings([]string{"corpus and other"})
+	if err != nil {
+		t.Fatalf("MatchStrings failed: %v", err)
+	}
+	if want := []string{"other"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("got: %#v, want: %#v", matches, want)
+	}
+}
+
+// Test_PooledEngineUpdateIncrementalRequiresPatternCache guards the
+// documented precondition that UpdateIncremental needs WithPatternCache
+func Test_PooledEngineUpdateIncrementalRequiresPatternCache(t *testing.T) {
+	t.Parallel()
+
+	var engine = NewPooledEngine(runtime.NumCPU())
+	engine.Start()
+	defer engine.Stop()
+
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var err = engine.UpdateIncremental([]string{"/other/"}, nil)
+	if err != errNoPatternCache {
+		t.Errorf("got: %v, want: %v", err, errNoPatternCache)
+	}
+}
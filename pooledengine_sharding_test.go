@@ -0,0 +1,37 @@
+package hypermatcher
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Test_PooledEngineMatchShardedFindsMatchesAcrossShards exercises
+// MatchSharded with a corpus large enough, and minShardSize small enough,
+// to force the corpus to actually be split across multiple workers
+func Test_PooledEngineMatchShardedFindsMatchesAcrossShards(t *testing.T) {
+	t.Parallel()
+
+	var warmUpTime = time.Millisecond
+	var engine = NewPooledEngine(runtime.NumCPU(), WithMinShardSize(1))
+	engine.Start()
+	defer engine.Stop()
+
+	if err := engine.Update([]string{"/corpus/", "/other/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	var corpus = [][]byte{[]byte("corpus"), []byte("other"), []byte("nothing")}
+	var matches, err = engine.MatchSharded(context.Background(), corpus)
+	if err != nil {
+		t.Fatalf("MatchSharded failed: %v", err)
+	}
+
+	var want = []string{"corpus", "other"}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got: %#v, want: %#v", matches, want)
+	}
+}
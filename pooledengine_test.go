@@ -6,6 +6,8 @@ import (
 	"runtime"
 	"testing"
 	"time"
+
+	"github.com/flier/gohs/hyperscan"
 )
 
 func Test_PooledEngineUpdatePatterns(t *testing.T) {
@@ -219,3 +221,70 @@ func Test_PooledEngineMatch(t *testing.T) {
 		}
 	}
 }
+
+// Test_PooledEngineCacheRotationReusesDatabaseAfterClose rotates between two
+// pattern sets with WithPatternCache enabled and returns to the first one a
+// third time, by which point its compiled database had already been closed
+// once if Update ever closed a database still tracked by the pattern cache
+// directly instead of leaving that to the cache's own eviction callback
+func Test_PooledEngineCacheRotationReusesDatabaseAfterClose(t *testing.T) {
+	t.Parallel()
+
+	var warmUpTime = time.Millisecond
+	var engine = NewPooledEngineWithCache(runtime.NumCPU(), 8)
+	engine.Start()
+	defer engine.Stop()
+
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update(A) failed: %v", err)
+	}
+	if err := engine.Update([]string{"/someotherkeyword/"}); err != nil {
+		t.Fatalf("Update(B) failed: %v", err)
+	}
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update(A) again failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	var matches, err = engine.MatchStrings([]string{"corpus"})
+	if err != nil {
+		t.Fatalf("MatchStrings against the re-cached database failed: %v", err)
+	}
+	if want := []string{"corpus"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("got: %#v, want: %#v", matches, want)
+	}
+}
+
+// Test_PooledEngineStopDoesNotCloseCachedDatabase guards against Stop
+// closing pe.db out from under the pattern cache when pe.db is still
+// reachable from pe.patternCache.dbs under its own fingerprint - only the
+// cache's own eviction callback may close it in that case
+func Test_PooledEngineStopDoesNotCloseCachedDatabase(t *testing.T) {
+	t.Parallel()
+
+	var warmUpTime = time.Millisecond
+	var engine = NewPooledEngineWithCache(runtime.NumCPU(), 8)
+	engine.Start()
+
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(warmUpTime)
+
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	var compiled, resolveErr = engine.patternCache.resolve("/corpus/")
+	if resolveErr != nil {
+		t.Fatalf("resolve failed: %v", resolveErr)
+	}
+	var db, cached = engine.patternCache.lookupDatabase([]*hyperscan.Pattern{compiled})
+	if !cached {
+		t.Fatal("got no cached database after Stop, want the fingerprint still cached")
+	}
+
+	if _, err := hyperscan.NewScratch(db); err != nil {
+		t.Errorf("NewScratch against the post-Stop cached database failed: %v, want it to still be open", err)
+	}
+}
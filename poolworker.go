@@ -1,6 +1,10 @@
 package hypermatcher
 
-import "github.com/flier/gohs/hyperscan"
+import (
+	"context"
+
+	"github.com/flier/gohs/hyperscan"
+)
 
 // poolWorker is a matching worker
 type poolWorker struct {
@@ -57,15 +61,42 @@ func (pw *poolWorker) onScan(request concurrentScanRequest) {
 		matched: make([]uint, 0),
 		err:     nil,
 	}
+	var ctx = request.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	response.err = pw.db.Scan(
 		request.blocks,
 		pw.scratch,
-		matchHandler,
+		poolMatchHandler(ctx),
 		&response.matched)
+	if response.err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			response.err = ctxErr
+		}
+	}
 
 	request.responseChan <- response
 }
 
+// poolMatchHandler returns a hyperscan match callback that accumulates
+// matched pattern IDs into the []uint passed as context, halting the scan
+// by returning a non-nil error as soon as ctx is done
+func poolMatchHandler(ctx context.Context) func(uint, uint64, uint64, uint, interface{}) error {
+	return func(id uint, from, to uint64, flags uint, context interface{}) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		var matched = context.(*[]uint)
+		*matched = append(*matched, id)
+
+		return nil
+	}
+}
+
 func (pw *poolWorker) onStop() {
 	pw.scratch.Free()
 }
@@ -0,0 +1,149 @@
+package remote
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/brknstrngz/hypermatcher"
+	"github.com/brknstrngz/hypermatcher/remote/pb"
+)
+
+// ErrSubscribeNotSupported is returned by Client's Subscribe, since match
+// subscriptions are delivered in-process on the engine that runs the scan
+// and there is no wire support (yet) for pushing them back across a Client's
+// unary RPCs
+var ErrSubscribeNotSupported = errString("subscriptions are not supported over a remote Client")
+
+// Client implements hypermatcher.Engine and hypermatcher.Database against a
+// remote Server, so callers can transparently swap a local hypermatcher.PooledEngine
+// for a remote one. It pools a small number of gRPC connections and
+// round-robins the unary Update/Match RPCs across them. MatchStream exists
+// on the wire for servers that want to pipeline many requests over one
+// connection, but Client does not use it yet - MatchContext always opens a
+// unary Match call
+type Client struct {
+	conns   []*grpc.ClientConn
+	clients []pb.MatcherServiceClient
+	next    uint64
+	mu      sync.Mutex
+}
+
+// Dial returns a Client load-balancing Match/Update calls across
+// numConns connections to target
+func Dial(target string, numConns int, opts ...grpc.DialOption) (*Client, error) {
+	if numConns < 1 {
+		numConns = 1
+	}
+
+	var c = &Client{
+		conns:   make([]*grpc.ClientConn, numConns),
+		clients: make([]pb.MatcherServiceClient, numConns),
+	}
+	for i := 0; i < numConns; i++ {
+		var conn, err = grpc.Dial(target, opts...)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.conns[i] = conn
+		c.clients[i] = pb.NewMatcherServiceClient(conn)
+	}
+
+	return c, nil
+}
+
+// Close releases all pooled connections, returning the first error
+// encountered, if any
+func (c *Client) Close() error {
+	var firstErr error
+	for _, conn := range c.conns {
+		if conn == nil {
+			continue
+		}
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// pick returns the next client connection in round-robin order
+func (c *Client) pick() pb.MatcherServiceClient {
+	c.mu.Lock()
+	var client = c.clients[c.next%uint64(len(c.clients))]
+	c.next++
+	c.mu.Unlock()
+
+	return client
+}
+
+// Update rebuilds the remote pattern database
+func (c *Client) Update(patterns []string) error {
+	return c.UpdateContext(context.Background(), patterns)
+}
+
+// UpdateContext is Update with a context for cancellation and deadlines
+func (c *Client) UpdateContext(ctx context.Context, patterns []string) error {
+	var resp, err = c.pick().Update(ctx, &pb.UpdateRequest{Patterns: patterns})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errString(resp.Error)
+	}
+
+	return nil
+}
+
+// Match takes a vectored byte corpus and returns a list of strings
+// representing patterns that matched the corpus and an optional error
+func (c *Client) Match(corpus [][]byte) ([]string, error) {
+	return c.MatchContext(context.Background(), corpus)
+}
+
+// MatchStrings takes a vectored string corpus and returns a list of strings
+// representing patterns that matched the corpus and an optional error
+func (c *Client) MatchStrings(corpus []string) ([]string, error) {
+	return c.MatchStringsContext(context.Background(), corpus)
+}
+
+// MatchContext is Match with a context for cancellation and deadlines
+func (c *Client) MatchContext(ctx context.Context, corpus [][]byte) ([]string, error) {
+	var resp, err = c.pick().Match(ctx, &pb.MatchRequest{Corpus: corpus})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errString(resp.Error)
+	}
+
+	return resp.Matches, nil
+}
+
+// MatchStringsContext is MatchStrings with a context for cancellation and deadlines
+func (c *Client) MatchStringsContext(ctx context.Context, corpus []string) ([]string, error) {
+	var blocks = make([][]byte, len(corpus))
+	for idx, element := range corpus {
+		blocks[idx] = []byte(element)
+	}
+
+	return c.MatchContext(ctx, blocks)
+}
+
+// Subscribe always fails with ErrSubscribeNotSupported; it exists so Client
+// satisfies hypermatcher.Engine
+func (c *Client) Subscribe(patternExpr string, handler func(hypermatcher.MatchEvent), opts ...hypermatcher.SubscriptionOption) (hypermatcher.SubID, error) {
+	return 0, ErrSubscribeNotSupported
+}
+
+// Unsubscribe is a no-op; Subscribe never hands out a usable SubID, so there
+// is never anything to unsubscribe
+func (c *Client) Unsubscribe(id hypermatcher.SubID) {}
+
+// errString turns a string received from the server back into an error
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,52 @@
+// Hand-written in lieu of protoc-gen-go (no protoc available in this
+// environment); DO regenerate with the command below once protoc is
+// available, which will replace this file entirely.
+// Regenerate with: protoc --go_out=. --go-grpc_out=. remote/matcher.proto
+//
+// Each message embeds the github.com/golang/protobuf-era v1 shape (exported
+// fields tagged with the classic `protobuf:"..."` struct tags, plus
+// Reset/String/ProtoMessage). google.golang.org/protobuf's legacy support
+// derives a protoreflect.Message from that tag information at runtime, which
+// is what lets these satisfy proto.Message and marshal over the wire -
+// unlike plain untagged structs, which grpc's codec rejects outright.
+
+package pb
+
+import "fmt"
+
+// UpdateRequest is the request message for MatcherService.Update
+type UpdateRequest struct {
+	Patterns []string `protobuf:"bytes,1,rep,name=patterns,proto3" json:"patterns,omitempty"`
+}
+
+func (x *UpdateRequest) Reset()         { *x = UpdateRequest{} }
+func (x *UpdateRequest) String() string { return fmt.Sprintf("%+v", *x) }
+func (*UpdateRequest) ProtoMessage()    {}
+
+// UpdateResponse is the response message for MatcherService.Update
+type UpdateResponse struct {
+	Error string `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *UpdateResponse) Reset()         { *x = UpdateResponse{} }
+func (x *UpdateResponse) String() string { return fmt.Sprintf("%+v", *x) }
+func (*UpdateResponse) ProtoMessage()    {}
+
+// MatchRequest is the request message for MatcherService.Match/MatchStream
+type MatchRequest struct {
+	Corpus [][]byte `protobuf:"bytes,1,rep,name=corpus,proto3" json:"corpus,omitempty"`
+}
+
+func (x *MatchRequest) Reset()         { *x = MatchRequest{} }
+func (x *MatchRequest) String() string { return fmt.Sprintf("%+v", *x) }
+func (*MatchRequest) ProtoMessage()    {}
+
+// MatchResponse is the response message for MatcherService.Match/MatchStream
+type MatchResponse struct {
+	Matches []string `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	Error   string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *MatchResponse) Reset()         { *x = MatchResponse{} }
+func (x *MatchResponse) String() string { return fmt.Sprintf("%+v", *x) }
+func (*MatchResponse) ProtoMessage()    {}
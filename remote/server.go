@@ -0,0 +1,76 @@
+// Package remote exposes a hypermatcher.Engine/Database pair as a gRPC
+// service, so a single process can host an expensive Hyperscan-compiled
+// database for many lightweight client instances to query over the network.
+package remote
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+
+	"github.com/brknstrngz/hypermatcher"
+	"github.com/brknstrngz/hypermatcher/remote/pb"
+)
+
+// Server implements pb.MatcherServiceServer on top of any
+// hypermatcher.Engine + hypermatcher.Database implementation, e.g. a
+// hypermatcher.PooledEngine
+type Server struct {
+	pb.UnimplementedMatcherServiceServer
+	engine   hypermatcher.Engine
+	database hypermatcher.Database
+}
+
+// NewServer returns a Server backed by engine/database
+func NewServer(engine hypermatcher.Engine, database hypermatcher.Database) *Server {
+	return &Server{engine: engine, database: database}
+}
+
+// Register registers the server with s2 under the MatcherService name
+func (s *Server) Register(s2 grpc.ServiceRegistrar) {
+	pb.RegisterMatcherServiceServer(s2, s)
+}
+
+// Update rebuilds the remote pattern database
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	if err := s.database.Update(req.Patterns); err != nil {
+		return &pb.UpdateResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.UpdateResponse{}, nil
+}
+
+// Match scans a single vectored corpus and returns matched expressions
+func (s *Server) Match(ctx context.Context, req *pb.MatchRequest) (*pb.MatchResponse, error) {
+	var matches, err = s.engine.MatchContext(ctx, req.Corpus)
+	if err != nil {
+		return &pb.MatchResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.MatchResponse{Matches: matches}, nil
+}
+
+// MatchStream pipelines many match requests over one connection; each
+// request is scanned and its response sent back in order, so a client with
+// many small corpora does not pay a round trip per corpus
+func (s *Server) MatchStream(stream pb.MatcherService_MatchStreamServer) error {
+	for {
+		var req, recvErr = stream.Recv()
+		if recvErr == io.EOF {
+			// the client closed its send side, ending the call cleanly
+			return nil
+		}
+		if recvErr != nil {
+			return recvErr
+		}
+
+		var resp, err = s.Match(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if sendErr := stream.Send(resp); sendErr != nil {
+			return sendErr
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/brknstrngz/hypermatcher"
+	"github.com/brknstrngz/hypermatcher/remote/pb"
+)
+
+// dialServer starts a Server backed by a fresh hypermatcher.Simple over an
+// in-memory listener and returns a Client dialed against it
+func dialServer(t *testing.T) *Client {
+	t.Helper()
+
+	var engine = hypermatcher.NewSimple()
+	var listener = bufconn.Listen(1024 * 1024)
+	var grpcServer = grpc.NewServer()
+	NewServer(engine, engine).Register(grpcServer)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	var dialer = func(context.Context, string) (net.Conn, error) { return listener.Dial() }
+	var client, err = Dial("bufnet", 1,
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// Test_ClientServerUpdateAndMatchRoundTrip drives a real Update/Match call
+// through the actual grpc proto codec end to end - this is exactly the path
+// that silently failed to marshal when pb's messages didn't satisfy
+// proto.Message
+func Test_ClientServerUpdateAndMatchRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var client = dialServer(t)
+
+	if err := client.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var matches, err = client.Match([][]byte{[]byte("corpus")})
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0] != "corpus" {
+		t.Errorf("got: %#v, want: [\"corpus\"]", matches)
+	}
+}
+
+// Test_ClientServerMatchStreamEndsCleanlyOnClose drives the bidirectional
+// MatchStream RPC directly against the server and confirms CloseSend ends
+// the call with a plain io.EOF from Recv, not a wrapped grpc status error -
+// which is what the server returned before it special-cased io.EOF on Recv
+func Test_ClientServerMatchStreamEndsCleanlyOnClose(t *testing.T) {
+	t.Parallel()
+
+	var client = dialServer(t)
+	if err := client.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var stream, streamErr = client.pick().MatchStream(context.Background())
+	if streamErr != nil {
+		t.Fatalf("MatchStream failed: %v", streamErr)
+	}
+
+	if err := stream.Send(&pb.MatchRequest{Corpus: [][]byte{[]byte("corpus")}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	var resp, recvErr = stream.Recv()
+	if recvErr != nil {
+		t.Fatalf("Recv failed: %v", recvErr)
+	}
+	if len(resp.Matches) != 1 || resp.Matches[0] != "corpus" {
+		t.Errorf("got: %#v, want: [\"corpus\"]", resp.Matches)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("got: %v, want: %v", err, io.EOF)
+	}
+}
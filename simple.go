@@ -2,6 +2,7 @@ package hypermatcher
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 	"runtime"
 	"sync"
@@ -14,9 +15,12 @@ import (
 // Simple is a basic Engine/Database implementation with a single hyperscan.Scratch protected by a mutex
 type Simple struct {
 	patterns []*hyperscan.Pattern
+	labels   []interface{}
 	db       hyperscan.VectoredDatabase
 	scratch  *hyperscan.Scratch
 	loaded   uint32
+	cache    *patternCache
+	subs     *subscriptions
 	mu       sync.RWMutex
 }
 
@@ -28,12 +32,38 @@ func NewSimple() *Simple {
 	}
 }
 
+// NewSimpleWithCache returns a Simple instance backed by an LRU cache of up
+// to size compiled databases, keyed by the fingerprint of the pattern set
+// they were built from. Update reuses a previously compiled database
+// whenever it is called again with a pattern set the cache still holds,
+// instead of recompiling it from scratch - useful when a server rotates
+// between a small, stable number of rule bundles
+func NewSimpleWithCache(size int) *Simple {
+	var cache, err = newPatternCache(size)
+	if err != nil {
+		return NewSimple()
+	}
+
+	return &Simple{
+		patterns: make([]*hyperscan.Pattern, 0),
+		cache:    cache,
+		mu:       sync.RWMutex{},
+	}
+}
+
 // Update rebuilds the pattern database, returning an optional error
 func (s *Simple) Update(patterns []string) error {
 	if len(patterns) == 0 {
 		return ErrNoPatterns
 	}
 
+	s.mu.RLock()
+	var cache = s.cache
+	s.mu.RUnlock()
+	if cache != nil {
+		return s.updateCached(patterns)
+	}
+
 	// compile patterns and add them to the internal list, returning
 	// an error on the first pattern that fails to parse
 	var compiledPatterns, compileErr = compilePatterns(patterns)
@@ -60,6 +90,7 @@ func (s *Simple) Update(patterns []string) error {
 	}
 	s.db = newDB
 	s.patterns = compiledPatterns
+	s.labels = nil
 	if s.scratch != nil {
 		s.scratch.Free()
 		s.scratch = nil
@@ -70,6 +101,51 @@ func (s *Simple) Update(patterns []string) error {
 	return nil
 }
 
+// updateCached is Update backed by s.cache: every pattern is resolved to a
+// stable ID (reparsing only on a cache miss), and the resulting pattern
+// set's database is reused if its ID fingerprint was compiled before
+func (s *Simple) updateCached(patterns []string) error {
+	var cache = s.cache
+
+	var compiledPatterns = make([]*hyperscan.Pattern, 0, len(patterns))
+	for _, raw := range patterns {
+		var compiled, compileErr = cache.resolve(raw)
+		if compileErr != nil {
+			return fmt.Errorf("error updating pattern database: %s", compileErr.Error())
+		}
+		compiledPatterns = append(compiledPatterns, compiled)
+	}
+
+	var newDB, cached = cache.lookupDatabase(compiledPatterns)
+	if !cached {
+		var built, dbErr = buildDatabase(compiledPatterns)
+		if dbErr != nil {
+			return fmt.Errorf("error updating pattern database: %s", dbErr.Error())
+		}
+		newDB = built
+		cache.storeDatabase(compiledPatterns, newDB)
+	}
+
+	var newScratch, scratchErr = hyperscan.NewScratch(newDB)
+	if scratchErr != nil {
+		return fmt.Errorf("error updating pattern database: %s", scratchErr.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var oldScratch = s.scratch
+	s.db = newDB
+	s.patterns = compiledPatterns
+	s.labels = nil
+	s.scratch = newScratch
+	s.setLoaded()
+	if oldScratch != nil {
+		oldScratch.Free()
+	}
+
+	return nil
+}
+
 // Close releases all resources used by the database, returning an optional error
 func (s *Simple) Close() error {
 	if !s.isLoaded() {
@@ -78,8 +154,12 @@ func (s *Simple) Close() error {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if err := s.db.Close(); err != nil {
-		return err
+	// s.db may still be reachable from s.cache.dbs under its own fingerprint;
+	// only the cache's own eviction callback may close it in that case
+	if s.cache == nil {
+		if err := s.db.Close(); err != nil {
+			return err
+		}
 	}
 
 	if s.scratch != nil {
@@ -125,6 +205,144 @@ func (s *Simple) MatchStrings(corpus []string) ([]string, error) {
 	return s.Match(stringsToByteSlices(corpus))
 }
 
+// MatchDetailed takes a vectored byte corpus and returns every match found,
+// including its pattern ID, expression, originating block index, byte
+// offsets and flags - unlike Match, hits are not deduplicated into a set of
+// expressions. The whole corpus is scanned in a single vectored Scan call,
+// the same way Match does it, so a pattern match spanning two blocks is
+// still reported; BlockIndex is recovered afterwards from the match offset
+func (s *Simple) MatchDetailed(corpus [][]byte) ([]Match, error) {
+	if !s.isLoaded() {
+		return nil, ErrNotLoaded
+	}
+
+	var blockLengths = make([]int, len(corpus))
+	for idx, block := range corpus {
+		blockLengths[idx] = len(block)
+	}
+
+	var matches = make([]Match, 0)
+	s.mu.Lock()
+	var subs = s.subs
+	var dmc = &detailedMatchContext{blockLengths: blockLengths, patterns: s.patterns, labels: s.labels, matches: &matches}
+	var scanErr = s.db.Scan(corpus, s.scratch, detailedMatchHandler, dmc)
+	s.mu.Unlock()
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	if subs != nil {
+		subs.publishAll(matches)
+	}
+
+	return matches, nil
+}
+
+// Subscribe registers handler to be invoked asynchronously, on a dedicated
+// dispatch goroutine, for every match of patternExpr found by a later
+// MatchDetailed/MatchStringsDetailed call. Returns ErrUnknownPattern if
+// patternExpr is not part of the currently loaded pattern set
+func (s *Simple) Subscribe(patternExpr string, handler func(MatchEvent), opts ...SubscriptionOption) (SubID, error) {
+	s.mu.RLock()
+	var patternID, err = resolvePatternID(s.patterns, patternExpr)
+	s.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = newSubscriptions()
+	}
+	var subs = s.subs
+	s.mu.Unlock()
+
+	return subs.subscribe(patternID, handler, opts...), nil
+}
+
+// Unsubscribe cancels a subscription previously returned by Subscribe
+func (s *Simple) Unsubscribe(id SubID) {
+	s.mu.RLock()
+	var subs = s.subs
+	s.mu.RUnlock()
+	if subs != nil {
+		subs.unsubscribe(id)
+	}
+}
+
+// MatchStringsDetailed is MatchDetailed over a string corpus
+func (s *Simple) MatchStringsDetailed(corpus []string) ([]Match, error) {
+	return s.MatchDetailed(stringsToByteSlices(corpus))
+}
+
+// UpdateWithLabels is Update, but binds each pattern to caller-supplied
+// metadata (a rule ID, severity, action code, etc.) that MatchDetailed
+// attaches to every Match produced by that pattern
+func (s *Simple) UpdateWithLabels(patterns []LabeledPattern) error {
+	var raw = make([]string, len(patterns))
+	var labels = make([]interface{}, len(patterns))
+	for idx, labeledPattern := range patterns {
+		raw[idx] = labeledPattern.Pattern
+		labels[idx] = labeledPattern.Label
+	}
+
+	if err := s.Update(raw); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.labels = labels
+	s.mu.Unlock()
+
+	return nil
+}
+
+// SaveDatabase writes the currently loaded pattern database to w so a later
+// process can skip recompiling the same patterns via LoadDatabase
+func (s *Simple) SaveDatabase(w io.Writer) error {
+	if !s.isLoaded() {
+		return ErrNotLoaded
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return saveDatabase(w, s.patterns, s.db)
+}
+
+// LoadDatabase replaces the pattern database with one previously written by
+// SaveDatabase, rejecting it if it was built for a CPU feature set this host
+// does not have
+func (s *Simple) LoadDatabase(r io.Reader) error {
+	var patterns, db, err = loadDatabase(r)
+	if err != nil {
+		return err
+	}
+
+	var newScratch, scratchErr = hyperscan.NewScratch(db)
+	if scratchErr != nil {
+		return scratchErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// as in Close, a database still reachable from s.cache.dbs is left for
+	// the cache's own eviction callback to close
+	if s.isLoaded() && s.cache == nil {
+		s.db.Close()
+	}
+	s.db = db
+	s.patterns = patterns
+	s.labels = nil
+	if s.scratch != nil {
+		s.scratch.Free()
+	}
+	s.scratch = newScratch
+	s.setLoaded()
+
+	return nil
+}
+
 func (s *Simple) isLoaded() bool {
 	return atomic.LoadUint32(&s.loaded) == 1
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/flier/gohs/hyperscan"
 )
 
 func Test_SimpleEngineUpdatePatterns(t *testing.T) {
@@ -151,3 +153,61 @@ func Test_SimpleEngineMatch(t *testing.T) {
 		}
 	}
 }
+
+// Test_SimpleMatchDetailedFindsMatchSpanningBlocks guards against
+// MatchDetailed missing a hit that straddles two blocks of a vectored
+// corpus, which a per-block Scan loop cannot see since hyperscan's vectored
+// mode only reports cross-block matches when given every block in one call
+func Test_SimpleMatchDetailedFindsMatchSpanningBlocks(t *testing.T) {
+	t.Parallel()
+
+	var engine = NewSimple()
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var matches, err = engine.MatchDetailed([][]byte{[]byte("cor"), []byte("pus")})
+	if err != nil {
+		t.Fatalf("MatchDetailed failed: %v", err)
+	}
+
+	var found bool
+	for _, match := range matches {
+		if match.Expression == "corpus" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("got %#v, want a match for \"corpus\" spanning the \"cor\"/\"pus\" block boundary", matches)
+	}
+}
+
+// Test_SimpleCloseDoesNotCloseCachedDatabase guards against Close closing
+// s.db out from under the pattern cache when s.db is still reachable from
+// s.cache.dbs under its own fingerprint - only the cache's own eviction
+// callback may close it in that case
+func Test_SimpleCloseDoesNotCloseCachedDatabase(t *testing.T) {
+	t.Parallel()
+
+	var engine = NewSimpleWithCache(8)
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if err := engine.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var compiled, resolveErr = engine.cache.resolve("/corpus/")
+	if resolveErr != nil {
+		t.Fatalf("resolve failed: %v", resolveErr)
+	}
+	var db, cached = engine.cache.lookupDatabase([]*hyperscan.Pattern{compiled})
+	if !cached {
+		t.Fatal("got no cached database after Close, want the fingerprint still cached")
+	}
+
+	if _, err := hyperscan.NewScratch(db); err != nil {
+		t.Errorf("NewScratch against the post-Close cached database failed: %v, want it to still be open", err)
+	}
+}
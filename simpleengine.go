@@ -1,7 +1,9 @@
 package hypermatcher
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"runtime"
 	"sync"
@@ -14,9 +16,11 @@ import (
 // SimpleEngine is a basic Engine implementation with a single hyperscan.Scratch protected by a mutex
 type SimpleEngine struct {
 	patterns []*hyperscan.Pattern
+	labels   []interface{}
 	db       hyperscan.VectoredDatabase
 	scratch  *hyperscan.Scratch
 	loaded   uint32
+	subs     *subscriptions
 	mu       sync.RWMutex
 }
 
@@ -59,6 +63,7 @@ func (se *SimpleEngine) Update(patterns []string) error {
 	}
 	se.db = newDB
 	se.patterns = compiledPatterns
+	se.labels = nil
 	if se.scratch != nil {
 		se.scratch.Free()
 	}
@@ -106,6 +111,203 @@ func (se *SimpleEngine) MatchStrings(corpus []string) ([]string, error) {
 	return se.Match(stringsToByteSlices(corpus))
 }
 
+// UpdateContext is Update with a context; ctx is only consulted before the
+// (synchronous, uncancellable) rebuild starts, so a caller can bail out of
+// a queued update without paying for a compile that is no longer wanted
+func (se *SimpleEngine) UpdateContext(ctx context.Context, patterns []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return se.Update(patterns)
+}
+
+// MatchContext is Match with a context; if ctx is cancelled or its deadline
+// expires before the scan completes, the hyperscan callback halts scanning
+// on its next invocation and ctx.Err() is returned
+func (se *SimpleEngine) MatchContext(ctx context.Context, corpus [][]byte) ([]string, error) {
+	// if the database has not yet been loaded, return an error
+	if !se.isLoaded() {
+		return nil, ErrNotLoaded
+	}
+
+	var matched = make(map[uint]struct{}, 0)
+	se.mu.Lock()
+	var scanErr = se.db.Scan(
+		corpus,
+		se.scratch,
+		matchHandlerContext(ctx),
+		&matched)
+	se.mu.Unlock()
+	if scanErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, scanErr
+	}
+
+	se.mu.RLock()
+	var matchedPatterns = matchedIdxToPatterns(matched, se.patterns)
+	se.mu.RUnlock()
+
+	return matchedPatterns, nil
+}
+
+// MatchStringsContext is MatchStrings with a context, see MatchContext
+func (se *SimpleEngine) MatchStringsContext(ctx context.Context, corpus []string) ([]string, error) {
+	return se.MatchContext(ctx, stringsToByteSlices(corpus))
+}
+
+// MatchDetailed takes a vectored byte corpus and returns every match found,
+// including its pattern ID, expression, originating block index, byte
+// offsets and flags - unlike Match, hits are not deduplicated into a set of
+// expressions. The whole corpus is scanned in a single vectored Scan call,
+// the same way Match does it, so a pattern match spanning two blocks is
+// still reported; BlockIndex is recovered afterwards from the match offset
+func (se *SimpleEngine) MatchDetailed(corpus [][]byte) ([]Match, error) {
+	if !se.isLoaded() {
+		return nil, ErrNotLoaded
+	}
+
+	var blockLengths = make([]int, len(corpus))
+	for idx, block := range corpus {
+		blockLengths[idx] = len(block)
+	}
+
+	var matches = make([]Match, 0)
+	se.mu.Lock()
+	var subs = se.subs
+	var dmc = &detailedMatchContext{blockLengths: blockLengths, patterns: se.patterns, labels: se.labels, matches: &matches}
+	var scanErr = se.db.Scan(corpus, se.scratch, detailedMatchHandler, dmc)
+	se.mu.Unlock()
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	if subs != nil {
+		subs.publishAll(matches)
+	}
+
+	return matches, nil
+}
+
+// Subscribe registers handler to be invoked asynchronously, on a dedicated
+// dispatch goroutine, for every match of patternExpr found by a later
+// MatchDetailed/MatchStringsDetailed call. Returns ErrUnknownPattern if
+// patternExpr is not part of the currently loaded pattern set
+func (se *SimpleEngine) Subscribe(patternExpr string, handler func(MatchEvent), opts ...SubscriptionOption) (SubID, error) {
+	se.mu.RLock()
+	var patternID, err = resolvePatternID(se.patterns, patternExpr)
+	se.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	se.mu.Lock()
+	if se.subs == nil {
+		se.subs = newSubscriptions()
+	}
+	var subs = se.subs
+	se.mu.Unlock()
+
+	return subs.subscribe(patternID, handler, opts...), nil
+}
+
+// Unsubscribe cancels a subscription previously returned by Subscribe
+func (se *SimpleEngine) Unsubscribe(id SubID) {
+	se.mu.RLock()
+	var subs = se.subs
+	se.mu.RUnlock()
+	if subs != nil {
+		subs.unsubscribe(id)
+	}
+}
+
+// MatchStringsDetailed is MatchDetailed over a string corpus
+func (se *SimpleEngine) MatchStringsDetailed(corpus []string) ([]Match, error) {
+	return se.MatchDetailed(stringsToByteSlices(corpus))
+}
+
+// UpdateWithLabels is Update, but binds each pattern to caller-supplied
+// metadata (a rule ID, severity, action code, etc.) that MatchDetailed
+// attaches to every Match produced by that pattern
+func (se *SimpleEngine) UpdateWithLabels(patterns []LabeledPattern) error {
+	var raw = make([]string, len(patterns))
+	var labels = make([]interface{}, len(patterns))
+	for idx, labeledPattern := range patterns {
+		raw[idx] = labeledPattern.Pattern
+		labels[idx] = labeledPattern.Label
+	}
+
+	if err := se.Update(raw); err != nil {
+		return err
+	}
+
+	se.mu.Lock()
+	se.labels = labels
+	se.mu.Unlock()
+
+	return nil
+}
+
+// SaveDatabase writes the currently loaded pattern database to w so a later
+// process can skip recompiling the same patterns via LoadDatabase
+func (se *SimpleEngine) SaveDatabase(w io.Writer) error {
+	if !se.isLoaded() {
+		return ErrNotLoaded
+	}
+
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	return saveDatabase(w, se.patterns, se.db)
+}
+
+// LoadDatabase replaces the pattern database with one previously written by
+// SaveDatabase, rejecting it if it was built for a CPU feature set this host
+// does not have
+func (se *SimpleEngine) LoadDatabase(r io.Reader) error {
+	var patterns, db, err = loadDatabase(r)
+	if err != nil {
+		return err
+	}
+
+	var newScratch, scratchErr = hyperscan.NewScratch(db)
+	if scratchErr != nil {
+		return scratchErr
+	}
+
+	se.mu.Lock()
+	if se.isLoaded() {
+		se.db.Close()
+	}
+	se.db = db
+	se.patterns = patterns
+	se.labels = nil
+	if se.scratch != nil {
+		se.scratch.Free()
+	}
+	se.scratch = newScratch
+	se.setLoaded()
+	se.mu.Unlock()
+
+	return nil
+}
+
+// matchHandlerContext returns a hyperscan match callback that aborts
+// scanning by returning a non-nil error as soon as ctx is done
+func matchHandlerContext(ctx context.Context) func(uint, uint64, uint64, uint, interface{}) error {
+	return func(id uint, from, to uint64, flags uint, context interface{}) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		return matchHandler(id, from, to, flags, context)
+	}
+}
+
 func (se *SimpleEngine) isLoaded() bool {
 	return atomic.LoadUint32(&se.loaded) == 1
 }
@@ -0,0 +1,235 @@
+package hypermatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/flier/gohs/hyperscan"
+)
+
+// ErrStreamsOpen is returned by StreamingEngine.Update when one or more
+// streams are still open: a stream's scratch is tied to the hyperscan.Stream
+// it was opened against, so swapping the underlying database out from under
+// it would corrupt in-flight state
+var ErrStreamsOpen = errors.New("cannot update database while streams are open")
+
+// StreamingEngine is a StreamingMatcher implementation built on hyperscan's native
+// streaming mode, for progressive/chunked input whose full extent can't be
+// materialized as a [][]byte corpus up front (e.g. a tailed log file or a
+// long-lived network connection). Unlike SimpleEngine/PooledEngine, which
+// use hyperscan.VectoredMode and require the whole corpus ahead of time,
+// StreamingEngine reports matches that span chunk boundaries
+type StreamingEngine struct {
+	patterns    []*hyperscan.Pattern
+	db          hyperscan.StreamDatabase
+	loaded      bool
+	openStreams int
+	mu          sync.RWMutex
+}
+
+// NewStreamingEngine returns a StreamingEngine
+func NewStreamingEngine() *StreamingEngine {
+	return &StreamingEngine{
+		patterns: make([]*hyperscan.Pattern, 0),
+	}
+}
+
+// Update rebuilds the pattern database, returning an optional error. It
+// fails with ErrStreamsOpen if any Stream opened from this engine has not
+// yet been closed
+func (se *StreamingEngine) Update(patterns []string) error {
+	if len(patterns) == 0 {
+		return ErrNoPatterns
+	}
+
+	var compiledPatterns, compileErr = compilePatterns(patterns)
+	if compileErr != nil {
+		return fmt.Errorf("error updating pattern database: %s", compileErr.Error())
+	}
+
+	var builder = &hyperscan.DatabaseBuilder{
+		Patterns: compiledPatterns,
+		Mode:     hyperscan.StreamMode,
+		Platform: hyperscan.PopulatePlatform(),
+	}
+	var db, buildErr = builder.Build()
+	if buildErr != nil {
+		return fmt.Errorf("error updating pattern database: %s", buildErr.Error())
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if se.openStreams > 0 {
+		db.Close()
+		return ErrStreamsOpen
+	}
+	if se.loaded {
+		se.db.Close()
+	}
+	se.db = db.(hyperscan.StreamDatabase)
+	se.patterns = compiledPatterns
+	se.loaded = true
+
+	return nil
+}
+
+// UpdateContext is Update with a context for cancellation and deadlines
+func (se *StreamingEngine) UpdateContext(ctx context.Context, patterns []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return se.Update(patterns)
+}
+
+// Open starts a new Stream against the currently loaded database. The
+// returned Stream owns its own hyperscan.Scratch, since scratch is not safe
+// to share across concurrently open streams
+func (se *StreamingEngine) Open(ctx context.Context) (*Stream, error) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if !se.loaded {
+		return nil, ErrNotLoaded
+	}
+
+	var scratch, scratchErr = hyperscan.NewScratch(se.db)
+	if scratchErr != nil {
+		return nil, scratchErr
+	}
+
+	var s = &Stream{
+		engine:  se,
+		scratch: scratch,
+		matched: make([]Match, 0),
+	}
+	var stream, openErr = se.db.Open(0, scratch, streamMatchHandler, s)
+	if openErr != nil {
+		scratch.Free()
+		return nil, openErr
+	}
+	s.stream = stream
+	se.openStreams++
+
+	return s, nil
+}
+
+// OpenWriter is Open, but returns an io.WriteCloser wrapping the stream so
+// callers can pipe a network socket or file reader directly into it, e.g.
+// via io.Copy. Matches are delivered to onMatch as they are found instead
+// of being returned from Write, since io.Writer.Write cannot return them
+func (se *StreamingEngine) OpenWriter(ctx context.Context, onMatch func(Match)) (io.WriteCloser, error) {
+	var stream, err = se.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamWriter{stream: stream, onMatch: onMatch}, nil
+}
+
+// streamWriter adapts a *Stream to io.WriteCloser, forwarding every match
+// found during a Write to onMatch as it happens
+type streamWriter struct {
+	stream  *Stream
+	onMatch func(Match)
+}
+
+// Write implements io.Writer
+func (w *streamWriter) Write(p []byte) (int, error) {
+	var matches, err = w.stream.Write(p)
+	if err != nil {
+		return 0, err
+	}
+	if w.onMatch != nil {
+		for _, match := range matches {
+			w.onMatch(match)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close implements io.Closer, forwarding any matches found while draining
+// buffered stream state to onMatch before releasing the stream
+func (w *streamWriter) Close() error {
+	var matches, err = w.stream.Close()
+	if w.onMatch != nil {
+		for _, match := range matches {
+			w.onMatch(match)
+		}
+	}
+
+	return err
+}
+
+// Stream is a single hyperscan streaming-mode session: successive Write
+// calls feed it chunks of an unbounded input, and matches - including ones
+// spanning chunk boundaries - are reported as they are found
+type Stream struct {
+	engine  *StreamingEngine
+	stream  hyperscan.Stream
+	scratch *hyperscan.Scratch
+	matched []Match
+	closed  bool
+	mu      sync.Mutex
+}
+
+// Write feeds p into the stream and returns any matches found while
+// scanning it
+func (s *Stream) Write(p []byte) (matches []Match, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("stream closed")
+	}
+
+	s.matched = s.matched[:0]
+	if scanErr := s.stream.Scan(p); scanErr != nil {
+		return nil, scanErr
+	}
+
+	return append([]Match(nil), s.matched...), nil
+}
+
+// Close terminates the stream, returning any matches found while draining
+// buffered state and releasing the stream's scratch space
+func (s *Stream) Close() ([]Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("stream already closed")
+	}
+
+	s.matched = s.matched[:0]
+	var closeErr = s.stream.Close()
+	s.scratch.Free()
+	s.closed = true
+
+	s.engine.mu.Lock()
+	s.engine.openStreams--
+	s.engine.mu.Unlock()
+
+	return append([]Match(nil), s.matched...), closeErr
+}
+
+// streamMatchHandler accumulates matches into the *Stream passed as context,
+// scoped to a single Write/Close call by the caller resetting s.matched first
+func streamMatchHandler(id uint, from, to uint64, flags uint, context interface{}) error {
+	var s = context.(*Stream)
+	var expr = ""
+	if int(id) < len(s.engine.patterns) {
+		expr = s.engine.patterns[id].Expression.String()
+	}
+
+	s.matched = append(s.matched, Match{
+		PatternID:  int(id),
+		Expression: expr,
+		From:       from,
+		To:         to,
+		Flags:      flags,
+	})
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package hypermatcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_StreamingEngineFindsMatchSpanningWrites(t *testing.T) {
+	t.Parallel()
+
+	var engine = NewStreamingEngine()
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var stream, openErr = engine.Open(context.Background())
+	if openErr != nil {
+		t.Fatalf("Open failed: %v", openErr)
+	}
+
+	var first, writeErr = stream.Write([]byte("cor"))
+	if writeErr != nil {
+		t.Fatalf("Write(\"cor\") failed: %v", writeErr)
+	}
+	if len(first) != 0 {
+		t.Errorf("got %#v, want no matches before the pattern is complete", first)
+	}
+
+	var second, secondErr = stream.Write([]byte("pus"))
+	if secondErr != nil {
+		t.Fatalf("Write(\"pus\") failed: %v", secondErr)
+	}
+
+	var closeMatches, closeErr = stream.Close()
+	if closeErr != nil {
+		t.Fatalf("Close failed: %v", closeErr)
+	}
+	var matches = append(append([]Match(nil), second...), closeMatches...)
+
+	var found bool
+	for _, match := range matches {
+		if match.Expression == "corpus" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("got %#v, want a match for \"corpus\" spanning the \"cor\"/\"pus\" write boundary", matches)
+	}
+}
+
+func Test_StreamingEngineUpdateRejectsWhileStreamsOpen(t *testing.T) {
+	t.Parallel()
+
+	var engine = NewStreamingEngine()
+	if err := engine.Update([]string{"/corpus/"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var stream, openErr = engine.Open(context.Background())
+	if openErr != nil {
+		t.Fatalf("Open failed: %v", openErr)
+	}
+	defer stream.Close()
+
+	var err = engine.Update([]string{"/other/"})
+	if !reflect.DeepEqual(err, ErrStreamsOpen) {
+		t.Errorf("got: %v, want: %v", err, ErrStreamsOpen)
+	}
+}
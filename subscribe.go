@@ -0,0 +1,160 @@
+package hypermatcher
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/flier/gohs/hyperscan"
+)
+
+// defaultSubscriberBuffer is the buffered channel depth used for a
+// subscription unless overridden with WithBufferSize
+const defaultSubscriberBuffer = 64
+
+// ErrUnknownPattern is returned by Subscribe when patternExpr is not part of
+// the engine's currently loaded pattern set
+var ErrUnknownPattern = errors.New("pattern not registered with the engine")
+
+// MatchEvent is delivered to a Subscribe handler for each hit of the
+// subscribed pattern produced by a detailed scan
+type MatchEvent struct {
+	Match
+}
+
+// SubID identifies a subscription returned by Subscribe, for use with Unsubscribe
+type SubID uint64
+
+// SubscriptionOption configures how a subscriber receives events
+type SubscriptionOption func(*subscriber)
+
+// WithBufferSize sets the subscriber's buffered channel depth; once full,
+// the subscriber's drop policy (see WithDropSlow) decides what happens to
+// further events. Defaults to defaultSubscriberBuffer
+func WithBufferSize(n int) SubscriptionOption {
+	return func(sub *subscriber) { sub.bufferSize = n }
+}
+
+// WithDropSlow makes publish drop an event for this subscriber rather than
+// block the scan that produced it when its buffer is full. The default is
+// to block, so no events are lost but a slow handler can stall the scanner
+func WithDropSlow() SubscriptionOption {
+	return func(sub *subscriber) { sub.dropSlow = true }
+}
+
+// subscriber dispatches MatchEvents for a single Subscribe call on its own
+// goroutine, reading from a bounded buffered channel so a slow handler
+// stalls the scanner that publishes events only once that buffer is full,
+// and not at all if the subscription was made with WithDropSlow
+type subscriber struct {
+	id         SubID
+	patternID  int
+	handler    func(MatchEvent)
+	bufferSize int
+	dropSlow   bool
+	events     chan MatchEvent
+	stopChan   chan struct{}
+}
+
+func (sub *subscriber) dispatch() {
+	for {
+		select {
+		case event := <-sub.events:
+			sub.handler(event)
+		case <-sub.stopChan:
+			return
+		}
+	}
+}
+
+// subscriptions is an engine's pattern-ID-keyed subscriber registry
+type subscriptions struct {
+	mu      sync.RWMutex
+	byID    map[int][]*subscriber
+	nextSub uint64
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{byID: make(map[int][]*subscriber)}
+}
+
+// subscribe registers handler to be called for every future match of the
+// pattern with ID patternID, and starts its dispatch goroutine
+func (s *subscriptions) subscribe(patternID int, handler func(MatchEvent), opts ...SubscriptionOption) SubID {
+	var sub = &subscriber{
+		id:         SubID(atomic.AddUint64(&s.nextSub, 1)),
+		patternID:  patternID,
+		handler:    handler,
+		bufferSize: defaultSubscriberBuffer,
+		stopChan:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.events = make(chan MatchEvent, sub.bufferSize)
+
+	go sub.dispatch()
+
+	s.mu.Lock()
+	s.byID[patternID] = append(s.byID[patternID], sub)
+	s.mu.Unlock()
+
+	return sub.id
+}
+
+// unsubscribe stops and removes the subscription identified by id
+func (s *subscriptions) unsubscribe(id SubID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for patternID, subs := range s.byID {
+		for idx, sub := range subs {
+			if sub.id == id {
+				close(sub.stopChan)
+				s.byID[patternID] = append(subs[:idx], subs[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publish delivers event to every subscriber registered for its pattern ID,
+// honoring each subscriber's drop policy
+func (s *subscriptions) publish(event MatchEvent) {
+	s.mu.RLock()
+	var subs = append([]*subscriber(nil), s.byID[event.PatternID]...)
+	s.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.dropSlow {
+			select {
+			case sub.events <- event:
+			default:
+			}
+			continue
+		}
+		select {
+		case sub.events <- event:
+		case <-sub.stopChan:
+		}
+	}
+}
+
+// publishAll is a convenience for publishing every match found by a
+// detailed scan in one call
+func (s *subscriptions) publishAll(matches []Match) {
+	for _, match := range matches {
+		s.publish(MatchEvent{match})
+	}
+}
+
+// resolvePatternID finds the ID of the pattern whose expression is
+// patternExpr, returning ErrUnknownPattern if it is not in patterns
+func resolvePatternID(patterns []*hyperscan.Pattern, patternExpr string) (int, error) {
+	for _, pattern := range patterns {
+		if pattern.Expression.String() == patternExpr {
+			return pattern.Id, nil
+		}
+	}
+
+	return 0, ErrUnknownPattern
+}
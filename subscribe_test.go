@@ -0,0 +1,85 @@
+package hypermatcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_SubscriptionsPublishDispatchesToMatchingPatternID(t *testing.T) {
+	t.Parallel()
+
+	var subs = newSubscriptions()
+	var received = make(chan MatchEvent, 1)
+	subs.subscribe(1, func(event MatchEvent) { received <- event })
+
+	subs.publish(MatchEvent{Match{PatternID: 0, Expression: "nope"}})
+	subs.publish(MatchEvent{Match{PatternID: 1, Expression: "yes"}})
+
+	select {
+	case event := <-received:
+		if event.Expression != "yes" {
+			t.Errorf("got event for %q, want %q", event.Expression, "yes")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler was not invoked for its subscribed pattern ID")
+	}
+
+	select {
+	case event := <-received:
+		t.Fatalf("got unexpected second event %#v, handler should not fire for a different pattern ID", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_SubscriptionsUnsubscribeStopsDelivery(t *testing.T) {
+	t.Parallel()
+
+	var subs = newSubscriptions()
+	var mu sync.Mutex
+	var calls int
+	var id = subs.subscribe(0, func(MatchEvent) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	})
+
+	subs.publish(MatchEvent{Match{PatternID: 0}})
+	time.Sleep(50 * time.Millisecond)
+	subs.unsubscribe(id)
+	subs.publish(MatchEvent{Match{PatternID: 0}})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1 (none after Unsubscribe)", calls)
+	}
+}
+
+func Test_SubscriptionsWithDropSlowDoesNotBlockPublish(t *testing.T) {
+	t.Parallel()
+
+	var subs = newSubscriptions()
+	var unblock = make(chan struct{})
+	subs.subscribe(0, func(MatchEvent) { <-unblock }, WithBufferSize(1), WithDropSlow())
+
+	var done = make(chan struct{})
+	go func() {
+		// the first publish is picked up by the blocked handler, the
+		// second fills the buffer, and the third would block forever
+		// without WithDropSlow
+		subs.publish(MatchEvent{Match{PatternID: 0}})
+		subs.publish(MatchEvent{Match{PatternID: 0}})
+		subs.publish(MatchEvent{Match{PatternID: 0}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a full buffer despite WithDropSlow")
+	}
+
+	close(unblock)
+}